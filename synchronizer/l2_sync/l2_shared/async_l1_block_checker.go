@@ -0,0 +1,223 @@
+package l2_shared
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/log"
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jackc/pgx/v4"
+)
+
+// defaultL1BlockCheckerBatchSize is the amount of L1 blocks checked on every round, so a single
+// round doesn't hold the state DB for too long
+const defaultL1BlockCheckerBatchSize = 100
+
+// defaultL1BlockCheckerYieldDelay is how long Run waits between rounds
+const defaultL1BlockCheckerYieldDelay = 500 * time.Millisecond
+
+// L1Interface contains the L1 methods required to detect silent reorgs
+type L1Interface interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// L1BlockCheckerStateInterface contains the state methods required by the AsyncL1BlockChecker
+type L1BlockCheckerStateInterface interface {
+	// GetLastNL1Blocks returns, ordered from newest to oldest, up to n L1 blocks already synced that
+	// have a block number lower than or equal to untilBlockNumber. untilBlockNumber == 0 means start
+	// from the newest synced block
+	GetLastNL1Blocks(ctx context.Context, n uint, untilBlockNumber uint64, dbTx pgx.Tx) ([]*state.Block, error)
+}
+
+// IterationResult is the outcome of a single AsyncL1BlockChecker round
+type IterationResult struct {
+	// ReorgDetected is true if a synced L1 block hash is no longer part of the L1 canonical chain
+	ReorgDetected bool
+	// BlockNumber is the L1 block number where the mismatch was detected (only set if ReorgDetected)
+	BlockNumber uint64
+	// LocalHash is the block hash we have stored for BlockNumber (only set if ReorgDetected)
+	LocalHash common.Hash
+	// CheckedBlockHashes are the block hashes checked on this round, newest to oldest
+	CheckedBlockHashes []common.Hash
+	// Err is set if the round couldn't be completed because of an unexpected error
+	Err error
+}
+
+// AsyncL1BlockChecker runs in the background, alongside SyncTrustedState, walking backwards through
+// the L1 blocks we have already synced and comparing them against L1 to detect silent reorgs that
+// happened while we were consuming trusted-state batches
+type AsyncL1BlockChecker interface {
+	// Run starts checking L1 blocks in the background until the context is done or Stop is called.
+	// onFinish is called (if not nil) after every round with that round IterationResult
+	Run(ctx context.Context, onFinish func(result IterationResult))
+	// RunSynchronous runs a single round and returns its result. It's the building block used by Run,
+	// exposed so callers that don't need the background loop can drive it themselves
+	RunSynchronous(ctx context.Context) IterationResult
+	// Stop stops the background loop started by Run. It's safe to call even if Run was never called
+	Stop()
+	// GetResult returns the IterationResult of the last round completed
+	GetResult() IterationResult
+}
+
+// NoOpAsyncL1BlockChecker is an AsyncL1BlockChecker that never checks anything. It's the default for
+// callers that don't need (or can't afford) the L1 reorg check
+type NoOpAsyncL1BlockChecker struct{}
+
+// NewNoOpAsyncL1BlockChecker creates a new NoOpAsyncL1BlockChecker
+func NewNoOpAsyncL1BlockChecker() *NoOpAsyncL1BlockChecker {
+	return &NoOpAsyncL1BlockChecker{}
+}
+
+// Run does nothing
+func (*NoOpAsyncL1BlockChecker) Run(ctx context.Context, onFinish func(result IterationResult)) {}
+
+// RunSynchronous does nothing and reports no reorg
+func (*NoOpAsyncL1BlockChecker) RunSynchronous(ctx context.Context) IterationResult {
+	return IterationResult{}
+}
+
+// Stop does nothing
+func (*NoOpAsyncL1BlockChecker) Stop() {}
+
+// GetResult always returns an empty result
+func (*NoOpAsyncL1BlockChecker) GetResult() IterationResult {
+	return IterationResult{}
+}
+
+// l1BlockChecker is the real AsyncL1BlockChecker implementation
+type l1BlockChecker struct {
+	l1        L1Interface
+	state     L1BlockCheckerStateInterface
+	batchSize uint
+	yield     time.Duration
+
+	// cursor is the block number the next round starts from (0 means start from the newest synced block)
+	cursor uint64
+
+	mu     sync.Mutex
+	result IterationResult
+
+	cancel context.CancelFunc
+}
+
+// NewAsyncL1BlockChecker creates a new AsyncL1BlockChecker that checks batchSize L1 blocks per round.
+// batchSize <= 0 falls back to a sane default
+func NewAsyncL1BlockChecker(l1 L1Interface, state L1BlockCheckerStateInterface, batchSize uint) AsyncL1BlockChecker {
+	if batchSize == 0 {
+		batchSize = defaultL1BlockCheckerBatchSize
+	}
+	return &l1BlockChecker{
+		l1:        l1,
+		state:     state,
+		batchSize: batchSize,
+		yield:     defaultL1BlockCheckerYieldDelay,
+	}
+}
+
+// Run starts a background loop that keeps calling RunSynchronous until ctx is done, Stop is called or
+// a round detects a reorg, yielding between rounds so it doesn't monopolize the state DB
+func (c *l1BlockChecker) Run(ctx context.Context, onFinish func(result IterationResult)) {
+	ctx, cancel := context.WithCancel(ctx)
+	c.cancel = cancel
+	go func() {
+		for {
+			result := c.RunSynchronous(ctx)
+			if onFinish != nil {
+				onFinish(result)
+			}
+			if result.ReorgDetected {
+				log.Warnf("asyncL1BlockChecker: reorg detected at L1 block %d, local hash %s", result.BlockNumber, result.LocalHash)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.yield):
+			}
+		}
+	}()
+}
+
+// Stop stops the background loop started by Run
+func (c *l1BlockChecker) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// GetResult returns the IterationResult of the last round completed
+func (c *l1BlockChecker) GetResult() IterationResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.result
+}
+
+// RunSynchronous checks a single batch of already-synced L1 blocks against L1, walking backwards from
+// c.cursor. Once it reaches the oldest synced block it wraps around and starts again from the tip, so
+// the whole recently-synced window keeps being re-checked over time
+func (c *l1BlockChecker) RunSynchronous(ctx context.Context) IterationResult {
+	blocks, err := c.state.GetLastNL1Blocks(ctx, c.batchSize, c.cursor, nil)
+	if err != nil {
+		return c.setResult(IterationResult{Err: err})
+	}
+	if len(blocks) == 0 {
+		c.cursor = 0
+		return c.setResult(IterationResult{})
+	}
+
+	checked := make([]common.Hash, 0, len(blocks))
+	for _, block := range blocks {
+		select {
+		case <-ctx.Done():
+			return c.setResult(IterationResult{Err: ctx.Err(), CheckedBlockHashes: checked})
+		default:
+		}
+
+		l1Block, err := c.l1.BlockByNumber(ctx, new(big.Int).SetUint64(block.BlockNumber))
+		if err != nil {
+			// couldn't reach L1 (timeout, rate limit, node restart, ...): this says nothing about
+			// whether a reorg happened, so don't treat it as one. Report it and retry next round
+			return c.setResult(IterationResult{Err: err, CheckedBlockHashes: checked})
+		}
+		if l1Block == nil || l1Block.Hash() != block.BlockHash {
+			// the block at this height doesn't match what we have locally: before declaring a reorg,
+			// make sure L1 has actually dropped our hash rather than us just failing to reach it. Only
+			// a definitive "no such header" confirms that; any other error is inconclusive
+			_, headerErr := c.l1.HeaderByHash(ctx, block.BlockHash)
+			switch {
+			case errors.Is(headerErr, ethereum.NotFound):
+				return c.setResult(IterationResult{
+					ReorgDetected:      true,
+					BlockNumber:        block.BlockNumber,
+					LocalHash:          block.BlockHash,
+					CheckedBlockHashes: append(checked, block.BlockHash),
+				})
+			case headerErr != nil:
+				return c.setResult(IterationResult{Err: headerErr, CheckedBlockHashes: checked})
+			}
+		}
+		checked = append(checked, block.BlockHash)
+	}
+
+	oldest := blocks[len(blocks)-1]
+	if oldest.BlockNumber == 0 {
+		c.cursor = 0
+	} else {
+		c.cursor = oldest.BlockNumber - 1
+	}
+	return c.setResult(IterationResult{CheckedBlockHashes: checked})
+}
+
+func (c *l1BlockChecker) setResult(result IterationResult) IterationResult {
+	c.mu.Lock()
+	c.result = result
+	c.mu.Unlock()
+	return result
+}
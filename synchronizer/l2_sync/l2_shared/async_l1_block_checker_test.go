@@ -0,0 +1,99 @@
+package l2_shared
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/jackc/pgx/v4"
+)
+
+type fakeL1 struct {
+	blockByNumberErr error
+	headerByHashErr  error
+	block            *types.Block
+}
+
+func (f *fakeL1) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	if f.blockByNumberErr != nil {
+		return nil, f.blockByNumberErr
+	}
+	return f.block, nil
+}
+
+func (f *fakeL1) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if f.headerByHashErr != nil {
+		return nil, f.headerByHashErr
+	}
+	return &types.Header{}, nil
+}
+
+type fakeL1BlockCheckerState struct {
+	blocks []*state.Block
+}
+
+func (f *fakeL1BlockCheckerState) GetLastNL1Blocks(ctx context.Context, n uint, untilBlockNumber uint64, dbTx pgx.Tx) ([]*state.Block, error) {
+	return f.blocks, nil
+}
+
+func TestRunSynchronousDistinguishesReorgFromTransientError(t *testing.T) {
+	localHash := common.HexToHash("0x1")
+	l1Hash := common.HexToHash("0x2")
+	localBlocks := []*state.Block{{BlockNumber: 10, BlockHash: localHash}}
+	rpcErr := errors.New("connection reset by peer")
+
+	testCases := []struct {
+		name              string
+		l1                *fakeL1
+		wantReorgDetected bool
+		wantErr           bool
+	}{
+		{
+			name:              "l1 confirms our hash is gone: genuine reorg",
+			l1:                &fakeL1{block: &types.Block{}, headerByHashErr: ethereum.NotFound},
+			wantReorgDetected: true,
+		},
+		{
+			name:              "BlockByNumber RPC error: transient, not a reorg",
+			l1:                &fakeL1{blockByNumberErr: rpcErr},
+			wantReorgDetected: false,
+			wantErr:           true,
+		},
+		{
+			name:              "HeaderByHash RPC error: can't confirm, not a reorg",
+			l1:                &fakeL1{block: &types.Block{}, headerByHashErr: rpcErr},
+			wantReorgDetected: false,
+			wantErr:           true,
+		},
+		{
+			name:              "L1 still knows our hash: benign race, not a reorg",
+			l1:                &fakeL1{block: &types.Block{}},
+			wantReorgDetected: false,
+			wantErr:           false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_ = l1Hash // unless it's set to fail, the fake's BlockByNumber returns a zero-value block,
+			// which never matches localHash, so every case that gets a block exercises the mismatch branch
+			c := &l1BlockChecker{
+				l1:        tc.l1,
+				state:     &fakeL1BlockCheckerState{blocks: localBlocks},
+				batchSize: defaultL1BlockCheckerBatchSize,
+			}
+			result := c.RunSynchronous(context.Background())
+			if result.ReorgDetected != tc.wantReorgDetected {
+				t.Errorf("ReorgDetected = %v, want %v", result.ReorgDetected, tc.wantReorgDetected)
+			}
+			if (result.Err != nil) != tc.wantErr {
+				t.Errorf("Err = %v, wantErr %v", result.Err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package l2_shared
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/0xPolygonHermez/zkevm-node/jsonrpc/types"
+	"github.com/0xPolygonHermez/zkevm-node/l1infotree"
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/jackc/pgx/v4"
+)
+
+// l1InfoTreeHeight is the fixed depth of the L1 info tree, matching the height the trusted node
+// builds it with
+const l1InfoTreeHeight = 32
+
+// PreProcessBatchChecker runs extra validations right before a trusted batch is handed to the
+// BatchExecutor for processing. localBatch is whatever is already stored locally for this batch
+// number, nil if nothing is
+type PreProcessBatchChecker interface {
+	// Check validates trustedBatch before it's processed. A non-nil error is treated as critical
+	Check(ctx context.Context, trustedBatch *types.Batch, localBatch *state.Batch, dbTx pgx.Tx) error
+}
+
+// PostClosedBatchChecker runs extra validations right after a trusted batch transitions from open to
+// closed, before its transaction is committed. localBatch is the locally computed batch that just
+// resulted from processing it
+type PostClosedBatchChecker interface {
+	// Check validates a closed trustedBatch against localBatch. A non-nil error is treated as critical
+	Check(ctx context.Context, trustedBatch *types.Batch, localBatch *state.Batch, dbTx pgx.Tx) error
+}
+
+// L1InfoRootCheckerStateInterface contains the state methods required by the L1InfoRootChecker
+type L1InfoRootCheckerStateInterface interface {
+	// GetLeavesByL1InfoRoot returns the ordered L1 info tree leaf hashes that make up l1InfoRoot
+	GetLeavesByL1InfoRoot(ctx context.Context, l1InfoRoot common.Hash, dbTx pgx.Tx) ([]common.Hash, error)
+}
+
+// L1InfoRootChecker is a PostClosedBatchChecker that recomputes the L1 info root from the leaves
+// stored for a closed batch and compares it against the one reported by the trusted node, catching
+// the class of bug the aggregator's leaves-by-l1-info-root path guards against
+type L1InfoRootChecker struct {
+	state L1InfoRootCheckerStateInterface
+}
+
+// NewL1InfoRootChecker creates a new L1InfoRootChecker
+func NewL1InfoRootChecker(state L1InfoRootCheckerStateInterface) *L1InfoRootChecker {
+	return &L1InfoRootChecker{state: state}
+}
+
+// Check fetches the leaves behind trustedBatch.L1InfoRoot and recomputes the root from them
+func (c *L1InfoRootChecker) Check(ctx context.Context, trustedBatch *types.Batch, localBatch *state.Batch, dbTx pgx.Tx) error {
+	leaves, err := c.state.GetLeavesByL1InfoRoot(ctx, trustedBatch.L1InfoRoot, dbTx)
+	if err != nil {
+		return fmt.Errorf("failed to get L1 info tree leaves for root %s: %w", trustedBatch.L1InfoRoot, err)
+	}
+	recomputedRoot, err := computeL1InfoRoot(leaves)
+	if err != nil {
+		return fmt.Errorf("failed to recompute L1 info root for batch %d: %w", trustedBatch.Number, err)
+	}
+	if recomputedRoot != trustedBatch.L1InfoRoot {
+		return fmt.Errorf("%w: batch %d: recomputed L1 info root %s doesn't match trusted L1 info root %s",
+			ErrTrustedBatchMismatch, trustedBatch.Number, recomputedRoot, trustedBatch.L1InfoRoot)
+	}
+	return nil
+}
+
+// computeL1InfoRoot rebuilds the L1 info tree root from its leaves by delegating to the same
+// l1infotree logic the trusted node builds its root with: a fixed 32-level binary keccak256 tree,
+// padded at each level with that level's recursively-defined zero-subtree hash (not a flat zero
+// value, since the hash of two zero leaves isn't zero). Re-deriving this by hand is easy to get
+// subtly wrong, so we build on top of the upstream implementation instead
+func computeL1InfoRoot(leaves []common.Hash) (common.Hash, error) {
+	mt, err := l1infotree.NewL1InfoTree(l1InfoTreeHeight, nil)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to initialize L1 info tree: %w", err)
+	}
+	rawLeaves := make([][32]byte, len(leaves))
+	for i, leaf := range leaves {
+		rawLeaves[i] = leaf
+	}
+	return mt.BuildL1InfoRoot(rawLeaves)
+}
+
+// AccInputHashChecker is a PostClosedBatchChecker that verifies the locally computed AccInputHash
+// matches the one reported by the trusted node for a batch that has just closed
+type AccInputHashChecker struct{}
+
+// NewAccInputHashChecker creates a new AccInputHashChecker
+func NewAccInputHashChecker() *AccInputHashChecker {
+	return &AccInputHashChecker{}
+}
+
+// Check compares localBatch.AccInputHash against trustedBatch.AccInputHash
+func (*AccInputHashChecker) Check(ctx context.Context, trustedBatch *types.Batch, localBatch *state.Batch, dbTx pgx.Tx) error {
+	if localBatch == nil {
+		return fmt.Errorf("%w: batch %d: no local batch to compare AccInputHash against", ErrTrustedBatchMismatch, trustedBatch.Number)
+	}
+	if localBatch.AccInputHash != trustedBatch.AccInputHash {
+		return fmt.Errorf("%w: batch %d: local AccInputHash %s doesn't match trusted AccInputHash %s",
+			ErrTrustedBatchMismatch, trustedBatch.Number, localBatch.AccInputHash, trustedBatch.AccInputHash)
+	}
+	return nil
+}
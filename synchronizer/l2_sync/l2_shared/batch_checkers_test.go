@@ -0,0 +1,69 @@
+package l2_shared
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Known-answer vectors computed with the real l1infotree.NewL1InfoTree(32, nil).BuildL1InfoRoot,
+// so a regression in computeL1InfoRoot's delegation or leaf conversion shows up as a mismatch here
+// rather than only at runtime against a trusted node.
+func TestComputeL1InfoRoot(t *testing.T) {
+	l0 := common.HexToHash("0x1")
+	l1 := common.HexToHash("0x2")
+	l2 := common.HexToHash("0x3")
+	l3 := common.HexToHash("0x4")
+	l4 := common.HexToHash("0x5")
+	l5 := common.HexToHash("0x6")
+	l6 := common.HexToHash("0x7")
+
+	testCases := []struct {
+		name   string
+		leaves []common.Hash
+		want   common.Hash
+	}{
+		{
+			name:   "empty",
+			leaves: nil,
+			want:   common.HexToHash("0x27ae5ba08d7291c96c8cbddcc148bf48a6d68c7974b94356f53754ef6171d757"),
+		},
+		{
+			name:   "single leaf",
+			leaves: []common.Hash{l0},
+			want:   common.HexToHash("0x21db8421fb719c4d28af3cda6aeee3388f75e2cc467bfc7b950d32a425f7d355"),
+		},
+		{
+			name:   "two leaves",
+			leaves: []common.Hash{l0, l1},
+			want:   common.HexToHash("0xed910e47f3c21d47debc7c730c32e06da6c54ba6b88b2378a61018f206903982"),
+		},
+		{
+			name:   "three leaves",
+			leaves: []common.Hash{l0, l1, l2},
+			want:   common.HexToHash("0x9384545e9aa4ebf1b8beb19916049a38744f06ef954a3f45560632d84ce6d533"),
+		},
+		{
+			name:   "five leaves",
+			leaves: []common.Hash{l0, l1, l2, l3, l4},
+			want:   common.HexToHash("0x5f213be2fc640249a552ca4702de66233832cb68b9df97e230cc872d5f6cb9f9"),
+		},
+		{
+			name:   "seven leaves",
+			leaves: []common.Hash{l0, l1, l2, l3, l4, l5, l6},
+			want:   common.HexToHash("0x351a89e8327bb977532b302b57e2245ad68d05e2750f3324179920200a0f638a"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := computeL1InfoRoot(tc.leaves)
+			if err != nil {
+				t.Fatalf("computeL1InfoRoot(%d leaves) returned error: %v", len(tc.leaves), err)
+			}
+			if got != tc.want {
+				t.Errorf("computeL1InfoRoot(%d leaves) = %s, want %s", len(tc.leaves), got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package l2_shared
+
+import (
+	"context"
+	"errors"
+
+	"github.com/0xPolygonHermez/zkevm-node/log"
+	"github.com/0xPolygonHermez/zkevm-node/synchronizer/metrics"
+)
+
+var (
+	// ErrMissingSyncFromL1 indicates that a permissionless node has fallen behind the trusted state
+	// (or lost track of what it can trust, e.g. because of an L1 reorg) and must resync from L1
+	// instead of continuing to consume the trusted-state RPC
+	ErrMissingSyncFromL1 = errors.New("missing sync from L1: node must resync from L1 before trusting the trusted state again")
+
+	// ErrFatalDesyncFromL1 indicates the trusted node reported data incompatible with what we already
+	// have finalized from L1 (a state-root/accInputHash mismatch on a batch that's already closed)
+	ErrFatalDesyncFromL1 = errors.New("fatal desync from L1: trusted state is incompatible with data already finalized from L1")
+)
+
+// CriticalErrorHandler reacts to critical errors found while syncing the trusted state. Trusted
+// sequencers and permissionless nodes need to react differently to the same error: a trusted
+// sequencer can't fall back to anything and must halt, a permissionless node can just resync from L1
+type CriticalErrorHandler interface {
+	// CriticalError handles a critical error detected while syncing the trusted state
+	CriticalError(ctx context.Context, err error)
+}
+
+// HaltHandler is a CriticalErrorHandler for trusted sequencers: it increments the halt metric, logs
+// the error and blocks forever, since a trusted sequencer has no L1 fallback to retry from
+type HaltHandler struct{}
+
+// NewHaltHandler creates a new HaltHandler
+func NewHaltHandler() *HaltHandler {
+	return &HaltHandler{}
+}
+
+// CriticalError increments the halt metric, logs the error and blocks forever
+func (*HaltHandler) CriticalError(ctx context.Context, err error) {
+	metrics.HaltCount()
+	log.Errorf("halting node: critical error syncing trusted state: %v", err)
+	select {}
+}
+
+// ResyncFromL1Handler is a CriticalErrorHandler for permissionless nodes: it logs the error, clears
+// the local trusted-state cache and returns, so the outer sync loop falls back to resyncing from L1
+type ResyncFromL1Handler struct {
+	sync *SyncTrustedStateTemplate
+}
+
+// NewResyncFromL1Handler creates a new ResyncFromL1Handler
+func NewResyncFromL1Handler(sync *SyncTrustedStateTemplate) *ResyncFromL1Handler {
+	return &ResyncFromL1Handler{sync: sync}
+}
+
+// CriticalError logs the error, clears the trusted-state cache and returns
+func (h *ResyncFromL1Handler) CriticalError(ctx context.Context, err error) {
+	log.Warnf("trusted state resync from L1 required: %v", err)
+	h.sync.CleanTrustedState()
+}
@@ -8,6 +8,7 @@ package l2_shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"time"
@@ -20,6 +21,14 @@ import (
 	"github.com/jackc/pgx/v4"
 )
 
+// ErrL1ReorgDetected is returned when the AsyncL1BlockChecker finds that an L1 block we already
+// synced is no longer part of the L1 canonical chain
+var ErrL1ReorgDetected = errors.New("L1 reorg detected: a previously synced L1 block hash is no longer canonical")
+
+// defaultPrefetchDepth is the default size of the prefetch channel used to overlap BatchByNumber
+// calls to the trusted node with the DB-bound processing of the previously fetched batch
+const defaultPrefetchDepth = 8
+
 // ZkEVMClientInterface contains the methods required to interact with zkEVM-RPC
 type ZkEVMClientInterface interface {
 	BatchNumber(ctx context.Context) (uint64, error)
@@ -36,10 +45,16 @@ type StateInterface interface {
 //
 //	this method is responsible to process a trusted batch
 type BatchExecutor interface {
-	// ProcessTrustedBatch processes a trusted batch
+	// ProcessTrustedBatch processes a trusted batch. It must return an error that wraps
+	// ErrTrustedBatchMismatch if the locally computed state root or accInputHash don't match the
+	// trusted batch ones
 	ProcessTrustedBatch(ctx context.Context, trustedBatch *types.Batch, status TrustedState, dbTx pgx.Tx) (*TrustedState, error)
 }
 
+// ErrTrustedBatchMismatch is returned (wrapped) by ProcessTrustedBatch when the locally computed
+// state root or accInputHash don't match the ones reported by the trusted node for that batch
+var ErrTrustedBatchMismatch = errors.New("trusted batch mismatch: local state root or accInputHash doesn't match the trusted one")
+
 // SyncInterface contains the methods required to interact with the synchronizer main class.
 type SyncInterface interface {
 	PendingFlushID(flushID uint64, proverID string)
@@ -67,22 +82,68 @@ type TrustedState struct {
 //
 //	and for each new batch calls the ProcessTrustedBatch method of the BatchExecutor interface
 type SyncTrustedStateTemplate struct {
-	steps        BatchExecutor
-	zkEVMClient  ZkEVMClientInterface
-	state        StateInterface
-	sync         SyncInterface
-	TrustedState TrustedState
+	steps                   BatchExecutor
+	zkEVMClient             ZkEVMClientInterface
+	state                   StateInterface
+	sync                    SyncInterface
+	TrustedState            TrustedState
+	l1BlockChecker          AsyncL1BlockChecker
+	criticalErrorHandler    CriticalErrorHandler
+	prefetchDepth           uint
+	preProcessBatchCheckers []PreProcessBatchChecker
+	postClosedBatchCheckers []PostClosedBatchChecker
 }
 
 // NewSyncTrustedStateTemplate creates a new SyncTrustedStateTemplate
 func NewSyncTrustedStateTemplate(steps BatchExecutor, zkEVMClient ZkEVMClientInterface, state StateInterface, sync SyncInterface) *SyncTrustedStateTemplate {
-	return &SyncTrustedStateTemplate{
-		steps:        steps,
-		zkEVMClient:  zkEVMClient,
-		state:        state,
-		sync:         sync,
-		TrustedState: TrustedState{},
+	s := &SyncTrustedStateTemplate{
+		steps:          steps,
+		zkEVMClient:    zkEVMClient,
+		state:          state,
+		sync:           sync,
+		TrustedState:   TrustedState{},
+		l1BlockChecker: NewNoOpAsyncL1BlockChecker(),
+		prefetchDepth:  defaultPrefetchDepth,
+	}
+	s.criticalErrorHandler = NewResyncFromL1Handler(s)
+	return s
+}
+
+// SetAsyncL1BlockChecker sets the AsyncL1BlockChecker to run alongside SyncTrustedState. It defaults
+// to a NoOpAsyncL1BlockChecker, so calling this is only needed to enable the L1 reorg check
+func (s *SyncTrustedStateTemplate) SetAsyncL1BlockChecker(checker AsyncL1BlockChecker) {
+	s.l1BlockChecker = checker
+}
+
+// SetCriticalErrorHandler sets the CriticalErrorHandler used when a critical error is found while
+// syncing the trusted state. It defaults to a ResyncFromL1Handler (permissionless node behaviour);
+// trusted sequencers should set a HaltHandler instead
+func (s *SyncTrustedStateTemplate) SetCriticalErrorHandler(handler CriticalErrorHandler) {
+	s.criticalErrorHandler = handler
+}
+
+// SetPrefetchDepth sets the size of the prefetch channel used to overlap BatchByNumber calls to the
+// trusted node with the processing of the previously fetched batch. It defaults to defaultPrefetchDepth;
+// depth == 0 falls back to that default
+func (s *SyncTrustedStateTemplate) SetPrefetchDepth(depth uint) {
+	if depth == 0 {
+		depth = defaultPrefetchDepth
 	}
+	s.prefetchDepth = depth
+}
+
+// AddPreChecker registers a PreProcessBatchChecker, run right before a trusted batch is handed to the
+// BatchExecutor for processing. Checkers run in registration order; the first error stops the sync
+// loop and is routed through the CriticalErrorHandler
+func (s *SyncTrustedStateTemplate) AddPreChecker(checker PreProcessBatchChecker) {
+	s.preProcessBatchCheckers = append(s.preProcessBatchCheckers, checker)
+}
+
+// AddPostChecker registers a PostClosedBatchChecker, run right after a trusted batch transitions from
+// open to closed. Checkers run in registration order; the first error stops the sync loop and is
+// routed through the CriticalErrorHandler
+func (s *SyncTrustedStateTemplate) AddPostChecker(checker PostClosedBatchChecker) {
+	s.postClosedBatchCheckers = append(s.postClosedBatchCheckers, checker)
 }
 
 // CleanTrustedState Clean cache of TrustedBatches and StateRoot
@@ -110,56 +171,220 @@ func (s *SyncTrustedStateTemplate) SyncTrustedState(ctx context.Context, latestS
 		log.Info("syncTrustedState: Trusted state is synchronized")
 		return nil
 	}
-	return s.syncTrustedBatchesToFrom(ctx, latestSyncedBatch, lastTrustedStateBatchNumber)
+
+	// syncCtx is canceled as soon as a reorg is detected, so an in-flight ProcessTrustedBatch call
+	// gets a chance to abort instead of running to completion before the next top-of-loop check
+	syncCtx, cancelSync := context.WithCancel(ctx)
+	defer cancelSync()
+
+	s.l1BlockChecker.Run(ctx, func(result IterationResult) {
+		if result.ReorgDetected {
+			cancelSync()
+		}
+	})
+	defer s.l1BlockChecker.Stop()
+
+	return s.syncTrustedBatchesToFrom(syncCtx, latestSyncedBatch, lastTrustedStateBatchNumber)
 }
 
 func isSyncrhonizedTrustedState(lastTrustedStateBatchNumber uint64, latestSyncedBatch uint64) bool {
 	return lastTrustedStateBatchNumber < latestSyncedBatch
 }
 
+// prefetchedBatch is a single result of the BatchByNumber prefetch pipeline
+type prefetchedBatch struct {
+	number uint64
+	batch  *types.Batch
+	err    error
+}
+
+// prefetchBatches starts a producer goroutine that walks from..to calling BatchByNumber and feeding
+// the results, in order, through the returned channel. It stops as soon as ctx is done, a fetch fails,
+// or the range is exhausted, closing the channel on its way out so callers can safely range over it
+func (s *SyncTrustedStateTemplate) prefetchBatches(ctx context.Context, from, to uint64) <-chan prefetchedBatch {
+	depth := s.prefetchDepth
+	if depth == 0 {
+		depth = defaultPrefetchDepth
+	}
+	out := make(chan prefetchedBatch, depth)
+	go func() {
+		defer close(out)
+		for number := from; number <= to; number++ {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			start := time.Now()
+			batch, err := s.zkEVMClient.BatchByNumber(ctx, big.NewInt(0).SetUint64(number))
+			metrics.GetTrustedBatchInfoTime(time.Since(start))
+
+			select {
+			case out <- prefetchedBatch{number: number, batch: batch, err: err}:
+				metrics.PrefetchQueueDepth(len(out))
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// drainPrefetch consumes and discards whatever is left in a prefetch channel, so the producer
+// goroutine (already stopped via ctx cancellation) can close it and exit without blocking on a send
+func drainPrefetch(prefetched <-chan prefetchedBatch) {
+	for range prefetched {
+	}
+}
+
 func (s *SyncTrustedStateTemplate) syncTrustedBatchesToFrom(ctx context.Context, latestSyncedBatch uint64, lastTrustedStateBatchNumber uint64) error {
+	prefetchCtx, cancelPrefetch := context.WithCancel(ctx)
+	defer cancelPrefetch()
+	prefetched := s.prefetchBatches(prefetchCtx, latestSyncedBatch, lastTrustedStateBatchNumber)
+
 	batchNumberToSync := latestSyncedBatch
 	for batchNumberToSync <= lastTrustedStateBatchNumber {
 		debugPrefix := fmt.Sprintf("syncTrustedState: batch[%d/%d]", batchNumberToSync, lastTrustedStateBatchNumber)
-		start := time.Now()
-		batchToSync, err := s.zkEVMClient.BatchByNumber(ctx, big.NewInt(0).SetUint64(batchNumberToSync))
-		metrics.GetTrustedBatchInfoTime(time.Since(start))
+
+		// ctx is already canceled by SyncTrustedState's onFinish callback as soon as the checker detects
+		// a reorg (see syncCtx there), so any ProcessTrustedBatch call in flight gets a chance to abort
+		// too; this check just turns that into the specific error/critical-error handling below
+		if checkerResult := s.l1BlockChecker.GetResult(); checkerResult.ReorgDetected {
+			err := fmt.Errorf("%w: L1 reorg detected at block %d (local hash %s): %v", ErrMissingSyncFromL1, checkerResult.BlockNumber, checkerResult.LocalHash, ErrL1ReorgDetected)
+			log.Errorf("%s aborting sync: %v", debugPrefix, err)
+			s.criticalErrorHandler.CriticalError(ctx, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
+			return err
+		}
+
+		next, ok := <-prefetched
+		if !ok {
+			err := fmt.Errorf("syncTrustedState: prefetch pipeline closed early before batch %d, ctx error: %v", batchNumberToSync, ctx.Err())
+			log.Errorf("%s %v", debugPrefix, err)
+			return err
+		}
+		batchToSync, err := next.batch, next.err
 		if err != nil {
 			log.Warnf("%s failed to get batch %d from trusted state. Error: %v", debugPrefix, batchNumberToSync, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
+			return err
+		}
+		if batchToSync == nil {
+			err := fmt.Errorf("syncTrustedState: trusted node returned a nil batch for batch number %d", batchNumberToSync)
+			log.Errorf("%s %v", debugPrefix, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return err
 		}
 
+		var stateCurrentBatch *state.Batch
+		if len(s.TrustedState.LastTrustedBatches) > 0 {
+			stateCurrentBatch = s.TrustedState.LastTrustedBatches[0]
+		}
+		if s.shouldSkipOpenBatch(batchToSync, stateCurrentBatch) {
+			log.Debugf("%s batch %d is still open on the trusted node with no new data since last sync, nothing to process", debugPrefix, batchNumberToSync)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
+			return nil
+		}
+
 		dbTx, err := s.state.BeginStateTransaction(ctx)
 		if err != nil {
 			log.Errorf("%s error creating db transaction to sync trusted batch %d: %v", debugPrefix, batchNumberToSync, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return err
 		}
-		start = time.Now()
+		start := time.Now()
 		cbatches, err := s.getCurrentBatches(ctx, s.TrustedState.LastTrustedBatches, batchToSync, dbTx)
 		if err != nil {
 			log.Errorf("%s error getting current batches to sync trusted batch %d: %v", debugPrefix, batchNumberToSync, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return rollback(ctx, dbTx, err)
 		}
 		previousStatus := TrustedState{
 			LastTrustedBatches: cbatches,
 			LastStateRoot:      s.TrustedState.LastStateRoot,
 		}
+
+		var localCurrentBatch *state.Batch
+		if len(cbatches) > 0 {
+			localCurrentBatch = cbatches[0]
+		}
+		for _, checker := range s.preProcessBatchCheckers {
+			if err := checker.Check(ctx, batchToSync, localCurrentBatch, dbTx); err != nil {
+				log.Errorf("%s pre-process check failed for batch %d: %v", debugPrefix, batchNumberToSync, err)
+				cancelPrefetch()
+				drainPrefetch(prefetched)
+				// roll back before invoking the handler: HaltHandler blocks forever and would
+				// otherwise leak this tx (and its DB connection) for the rest of the process's life
+				rbErr := rollback(ctx, dbTx, err)
+				s.criticalErrorHandler.CriticalError(ctx, err)
+				return rbErr
+			}
+		}
+
 		log.Debugf("%s processing trusted batch %d", debugPrefix, batchNumberToSync)
 		newTrustedState, err := s.steps.ProcessTrustedBatch(ctx, batchToSync, previousStatus, dbTx)
 		metrics.ProcessTrustedBatchTime(time.Since(start))
 		if err != nil {
+			if errors.Is(err, ErrTrustedBatchMismatch) && batchToSync.Closed {
+				fatalErr := fmt.Errorf("%w: batch %d is already closed: %v", ErrFatalDesyncFromL1, batchNumberToSync, err)
+				log.Errorf("%s %v", debugPrefix, fatalErr)
+				cancelPrefetch()
+				drainPrefetch(prefetched)
+				// roll back before invoking the handler: HaltHandler blocks forever and would
+				// otherwise leak this tx (and its DB connection) for the rest of the process's life
+				rbErr := rollback(ctx, dbTx, fatalErr)
+				s.criticalErrorHandler.CriticalError(ctx, fatalErr)
+				return rbErr
+			}
 			log.Errorf("%s error processing trusted batch %d: %v", debugPrefix, batchNumberToSync, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return rollback(ctx, dbTx, err)
 		}
+
+		if batchToSync.Closed {
+			var newLocalBatch *state.Batch
+			if len(newTrustedState.LastTrustedBatches) > 0 {
+				newLocalBatch = newTrustedState.LastTrustedBatches[0]
+			}
+			for _, checker := range s.postClosedBatchCheckers {
+				if err := checker.Check(ctx, batchToSync, newLocalBatch, dbTx); err != nil {
+					fatalErr := fmt.Errorf("%w: post-close check failed for batch %d: %v", ErrFatalDesyncFromL1, batchNumberToSync, err)
+					log.Errorf("%s %v", debugPrefix, fatalErr)
+					cancelPrefetch()
+					drainPrefetch(prefetched)
+					// roll back before invoking the handler: HaltHandler blocks forever and would
+					// otherwise leak this tx (and its DB connection) for the rest of the process's life
+					rbErr := rollback(ctx, dbTx, fatalErr)
+					s.criticalErrorHandler.CriticalError(ctx, fatalErr)
+					return rbErr
+				}
+			}
+		}
+
 		log.Debug("%s Checking FlushID to commit trustedState data to db", debugPrefix)
 		err = s.sync.CheckFlushID(dbTx)
 		if err != nil {
 			log.Errorf("%s error checking flushID. Error: %v", debugPrefix, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return rollback(ctx, dbTx, err)
 		}
 
 		if err := dbTx.Commit(ctx); err != nil {
 			log.Errorf("%s error committing db transaction to sync trusted batch %v: %v", debugPrefix, batchNumberToSync, err)
+			cancelPrefetch()
+			drainPrefetch(prefetched)
 			return err
 		}
 		//s.TrustedState.LastTrustedBatches = cbatches
@@ -172,6 +397,17 @@ func (s *SyncTrustedStateTemplate) syncTrustedBatchesToFrom(ctx context.Context,
 	return nil
 }
 
+// shouldSkipOpenBatch reports whether trustedBatch is still open on the trusted node and identical to
+// what's already stored locally for it (stateCurrentBatch): same batch number, same AccInputHash. In
+// that case there's no new data to process, so the caller can skip re-executing it until it changes
+// or closes (the batch is still fetched from the trusted node to make this comparison)
+func (s *SyncTrustedStateTemplate) shouldSkipOpenBatch(trustedBatch *types.Batch, stateCurrentBatch *state.Batch) bool {
+	if trustedBatch.Closed || stateCurrentBatch == nil {
+		return false
+	}
+	return stateCurrentBatch.BatchNumber == uint64(trustedBatch.Number) && stateCurrentBatch.AccInputHash == trustedBatch.AccInputHash
+}
+
 func rollback(ctx context.Context, dbTx pgx.Tx, err error) error {
 	rollbackErr := dbTx.Rollback(ctx)
 	if rollbackErr != nil {
@@ -205,4 +441,4 @@ func (s *SyncTrustedStateTemplate) getCurrentBatches(ctx context.Context, batche
 		batches = []*state.Batch{batch, prevBatch}
 	}
 	return batches, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,62 @@
+package l2_shared
+
+import (
+	"testing"
+
+	"github.com/0xPolygonHermez/zkevm-node/jsonrpc/types"
+	"github.com/0xPolygonHermez/zkevm-node/state"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestShouldSkipOpenBatch(t *testing.T) {
+	hashA := common.HexToHash("0x1")
+	hashB := common.HexToHash("0x2")
+
+	testCases := []struct {
+		name              string
+		trustedBatch      *types.Batch
+		stateCurrentBatch *state.Batch
+		expected          bool
+	}{
+		{
+			name:              "nothing stored locally yet",
+			trustedBatch:      &types.Batch{Number: 5, AccInputHash: hashA, Closed: false},
+			stateCurrentBatch: nil,
+			expected:          false,
+		},
+		{
+			name:              "closed on the trusted node",
+			trustedBatch:      &types.Batch{Number: 5, AccInputHash: hashA, Closed: true},
+			stateCurrentBatch: &state.Batch{BatchNumber: 5, AccInputHash: hashA},
+			expected:          false,
+		},
+		{
+			name:              "open, different batch number stored locally",
+			trustedBatch:      &types.Batch{Number: 5, AccInputHash: hashA, Closed: false},
+			stateCurrentBatch: &state.Batch{BatchNumber: 4, AccInputHash: hashA},
+			expected:          false,
+		},
+		{
+			name:              "open, trusted node appended new data",
+			trustedBatch:      &types.Batch{Number: 5, AccInputHash: hashB, Closed: false},
+			stateCurrentBatch: &state.Batch{BatchNumber: 5, AccInputHash: hashA},
+			expected:          false,
+		},
+		{
+			name:              "open and unchanged since last sync",
+			trustedBatch:      &types.Batch{Number: 5, AccInputHash: hashA, Closed: false},
+			stateCurrentBatch: &state.Batch{BatchNumber: 5, AccInputHash: hashA},
+			expected:          true,
+		},
+	}
+
+	s := &SyncTrustedStateTemplate{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := s.shouldSkipOpenBatch(tc.trustedBatch, tc.stateCurrentBatch)
+			if got != tc.expected {
+				t.Errorf("shouldSkipOpenBatch() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/0xPolygonHermez/zkevm-node/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// Prefix for the metrics of the synchronizer package.
+	Prefix = "synchronizer_"
+	// GetTrustedBatchInfoTimeName is the name of the metric that tracks the time spent getting a
+	// batch from the trusted node.
+	GetTrustedBatchInfoTimeName = Prefix + "get_trusted_batch_info_time"
+	// ProcessTrustedBatchTimeName is the name of the metric that tracks the time spent processing a
+	// trusted batch.
+	ProcessTrustedBatchTimeName = Prefix + "process_trusted_batch_time"
+	// HaltCountName is the name of the metric that counts the times the synchronizer has halted.
+	HaltCountName = Prefix + "halt_count"
+	// PrefetchQueueDepthName is the name of the metric that tracks how many trusted batches are
+	// sitting in the prefetch queue, waiting to be processed.
+	PrefetchQueueDepthName = Prefix + "prefetch_queue_depth"
+)
+
+// Register the metrics for the synchronizer package.
+func Register() {
+	histograms := []prometheus.HistogramOpts{
+		{
+			Name: GetTrustedBatchInfoTimeName,
+			Help: "[SYNCHRONIZER] time spent getting a batch from the trusted node",
+		},
+		{
+			Name: ProcessTrustedBatchTimeName,
+			Help: "[SYNCHRONIZER] time spent processing a trusted batch",
+		},
+		{
+			Name: PrefetchQueueDepthName,
+			Help: "[SYNCHRONIZER] number of trusted batches queued in the prefetch channel",
+		},
+	}
+	metrics.RegisterHistograms(histograms...)
+
+	counters := []prometheus.CounterOpts{
+		{
+			Name: HaltCountName,
+			Help: "[SYNCHRONIZER] total count of halt",
+		},
+	}
+	metrics.RegisterCounters(counters...)
+}
+
+// GetTrustedBatchInfoTime observes the time spent getting a batch from the trusted node.
+func GetTrustedBatchInfoTime(duration time.Duration) {
+	metrics.HistogramObserve(GetTrustedBatchInfoTimeName, duration.Seconds())
+}
+
+// ProcessTrustedBatchTime observes the time spent processing a trusted batch.
+func ProcessTrustedBatchTime(duration time.Duration) {
+	metrics.HistogramObserve(ProcessTrustedBatchTimeName, duration.Seconds())
+}
+
+// HaltCount increases the counter for the times the synchronizer has halted.
+func HaltCount() {
+	metrics.CounterAdd(HaltCountName, 1)
+}
+
+// PrefetchQueueDepth observes how many trusted batches are currently queued in the prefetch channel.
+func PrefetchQueueDepth(depth int) {
+	metrics.HistogramObserve(PrefetchQueueDepthName, float64(depth))
+}